@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/rtx/relay/internal/auth"
+	"github.com/rtx/relay/internal/backplane"
 	"github.com/rtx/relay/internal/broker"
+	"github.com/rtx/relay/internal/recorder"
+	"github.com/rtx/relay/internal/registry"
 	tlsutil "github.com/rtx/relay/internal/tls"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -22,16 +30,31 @@ type Config struct {
 	Port                   int    `yaml:"port"`
 	TLSCertPath           string `yaml:"tls_cert_path"`
 	TLSKeyPath            string `yaml:"tls_key_path"`
+	ClientCAPath          string `yaml:"client_ca_path"` // enables mutual TLS when set
 	JWTSecret             string `yaml:"jwt_secret"`
 	MaxConnectionsPerHost int    `yaml:"max_connections_per_host"`
 	ConnectionTimeout     int    `yaml:"connection_timeout"`
 	KeepaliveInterval     int    `yaml:"keepalive_interval"`
 	LogLevel              string `yaml:"log_level"`
+	Devices               map[string]string `yaml:"devices"` // hostID -> authorized device key
+	RecordingDir          string   `yaml:"recording_dir"`   // where session recordings are written; recording disabled if empty
+	RecordHosts           []string `yaml:"record_hosts"`    // hostIDs to record
+	ReadLimitBytes        int64    `yaml:"read_limit_bytes"`         // max WebSocket message size; 0 uses the broker default
+	RateLimitBytesPerSec  float64  `yaml:"rate_limit_bytes_per_sec"` // per-connection throughput cap; 0 disables
+	RateLimitMsgsPerSec   float64  `yaml:"rate_limit_msgs_per_sec"`  // per-connection message rate cap; 0 disables
+	SendBackpressureSecs  int      `yaml:"send_backpressure_seconds"` // how long to block a slow connection before dropping it; 0 uses the broker default
+
+	ClusterBackend         string `yaml:"cluster_backend"`             // "redis", "nats", or "" to run single-node
+	ClusterNodeID          string `yaml:"cluster_node_id"`             // identifies this node in presence records; defaults to the OS hostname
+	ClusterPresenceTTLSecs int    `yaml:"cluster_presence_ttl_seconds"` // 0 uses the broker default (also used as the NATS presence bucket's TTL)
+	RedisAddr              string `yaml:"redis_addr"`                  // used when cluster_backend is "redis"
+	NatsURL                string `yaml:"nats_url"`                    // used when cluster_backend is "nats"
 }
 
 var (
 	configFile = flag.String("config", "config.yaml", "Path to configuration file")
 	genToken   = flag.String("gen-token", "", "Generate connect token for host_id:device_key")
+	genRole    = flag.String("role", string(auth.RoleController), "Role for -gen-token: controller, viewer, or recorder")
 )
 
 func main() {
@@ -54,20 +77,61 @@ func main() {
 		logger.SetLevel(level)
 	}
 
+	// The audit trail is always JSON, independent of the operational log
+	// format, so downstream tooling can parse it reliably.
+	auditLogger := logrus.New()
+	auditLogger.SetFormatter(&logrus.JSONFormatter{})
+
 	// Handle token generation
 	if *genToken != "" {
-		generateToken(config, *genToken, logger)
+		generateToken(config, *genToken, auth.Role(*genRole), logger)
 		return
 	}
 
 	// Load TLS configuration
-	tlsConfig, err := tlsutil.LoadTLSConfig(config.TLSCertPath, config.TLSKeyPath)
+	tlsManager, err := tlsutil.NewManager(config.TLSCertPath, config.TLSKeyPath, config.ClientCAPath)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to load TLS configuration")
 	}
 
+	// Set up session recording, if configured
+	var sessionRecorder *recorder.FileRecorder
+	recordHosts := make(map[string]bool, len(config.RecordHosts))
+	if config.RecordingDir != "" {
+		sessionRecorder, err = recorder.NewFileRecorder(config.RecordingDir)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to set up session recorder")
+		}
+		for _, hostID := range config.RecordHosts {
+			recordHosts[hostID] = true
+		}
+	}
+
+	// Set up the cluster backplane, if configured, so hosts and clients
+	// landing on different relay processes can still reach each other.
+	cluster, err := newClusterConfig(config)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to set up cluster backplane")
+	}
+	if cluster != nil {
+		defer cluster.Backplane.Close()
+	}
+
 	// Create broker
-	relayBroker := broker.NewBroker(logger)
+	jwtManager := auth.NewJWTManager(config.JWTSecret)
+	deviceRegistry := registry.NewStaticRegistry(config.Devices)
+	limits := broker.Limits{
+		ReadLimitBytes: config.ReadLimitBytes,
+		BytesPerSec:    config.RateLimitBytesPerSec,
+		MsgsPerSec:     config.RateLimitMsgsPerSec,
+		SendDeadline:   time.Duration(config.SendBackpressureSecs) * time.Second,
+	}
+	var relayBroker *broker.Broker
+	if sessionRecorder != nil {
+		relayBroker = broker.NewBroker(logger, auditLogger, jwtManager, deviceRegistry, sessionRecorder, recordHosts, limits, cluster)
+	} else {
+		relayBroker = broker.NewBroker(logger, auditLogger, jwtManager, deviceRegistry, nil, nil, limits, cluster)
+	}
 
 	// Set up HTTP server
 	mux := http.NewServeMux()
@@ -76,12 +140,18 @@ func main() {
 	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
 		handleStats(w, r, relayBroker)
 	})
+	mux.Handle("/metrics", promhttp.Handler())
+	if sessionRecorder != nil {
+		mux.HandleFunc("/sessions/", func(w http.ResponseWriter, r *http.Request) {
+			handleSessionReplay(w, r, sessionRecorder, jwtManager, deviceRegistry)
+		})
+	}
 
 	addr := fmt.Sprintf(":%d", config.Port)
 	server := &http.Server{
 		Addr:      addr,
 		Handler:   mux,
-		TLSConfig: tlsConfig,
+		TLSConfig: tlsManager.TLSConfig(),
 		ReadTimeout:  time.Duration(config.ConnectionTimeout) * time.Second,
 		WriteTimeout: time.Duration(config.ConnectionTimeout) * time.Second,
 		IdleTimeout:  time.Duration(config.ConnectionTimeout*2) * time.Second,
@@ -95,6 +165,20 @@ func main() {
 		}
 	}()
 
+	// SIGHUP reloads the server cert and client CA pool without a restart,
+	// so operators can rotate device certs during normal operation.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := tlsManager.Reload(); err != nil {
+				logger.WithError(err).Error("Failed to reload TLS configuration")
+				continue
+			}
+			logger.Info("Reloaded TLS configuration")
+		}
+	}()
+
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -102,6 +186,11 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
+	// Withdraw this node's presence and close every connection before the
+	// HTTP server stops accepting, so in cluster mode other nodes stop
+	// routing to it immediately rather than waiting out the presence TTL.
+	relayBroker.Drain()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -113,6 +202,50 @@ func main() {
 	logger.Info("Server stopped")
 }
 
+// newClusterConfig builds a broker.ClusterConfig from config.ClusterBackend,
+// or returns nil if clustering is disabled (the default).
+func newClusterConfig(config *Config) (*broker.ClusterConfig, error) {
+	nodeID := config.ClusterNodeID
+	if nodeID == "" {
+		nodeID, _ = os.Hostname()
+	}
+
+	switch config.ClusterBackend {
+	case "":
+		return nil, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: config.RedisAddr})
+		return &broker.ClusterConfig{
+			Backplane:   backplane.NewRedis(client),
+			NodeID:      nodeID,
+			PresenceTTL: time.Duration(config.ClusterPresenceTTLSecs) * time.Second,
+		}, nil
+	case "nats":
+		conn, err := natsgo.Connect(config.NatsURL)
+		if err != nil {
+			return nil, fmt.Errorf("connect to NATS: %w", err)
+		}
+		presenceTTL := time.Duration(config.ClusterPresenceTTLSecs) * time.Second
+		if presenceTTL <= 0 {
+			// The broker's default presence TTL; a zero bucket TTL would
+			// make presence records never expire.
+			presenceTTL = 15 * time.Second
+		}
+		bp, err := backplane.NewNATS(conn, presenceTTL)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("set up NATS backplane: %w", err)
+		}
+		return &broker.ClusterConfig{
+			Backplane:   bp,
+			NodeID:      nodeID,
+			PresenceTTL: presenceTTL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown cluster_backend %q: must be \"redis\", \"nats\", or empty", config.ClusterBackend)
+	}
+}
+
 func loadConfig(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -127,7 +260,7 @@ func loadConfig(filename string) (*Config, error) {
 	return &config, nil
 }
 
-func generateToken(config *Config, hostDevice string, logger *logrus.Logger) {
+func generateToken(config *Config, hostDevice string, role auth.Role, logger *logrus.Logger) {
 	// Parse host_id:device_key
 	var hostID, deviceKey string
 	if n, err := fmt.Sscanf(hostDevice, "%s:%s", &hostID, &deviceKey); err != nil || n != 2 {
@@ -136,12 +269,12 @@ func generateToken(config *Config, hostDevice string, logger *logrus.Logger) {
 
 	// Generate token
 	jwtManager := auth.NewJWTManager(config.JWTSecret)
-	token, err := jwtManager.GenerateConnectToken(hostID, deviceKey)
+	token, err := jwtManager.GenerateClientToken(hostID, deviceKey, role)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to generate token")
 	}
 
-	fmt.Printf("Connect token: %s\n", token)
+	fmt.Printf("Connect token (role=%s): %s\n", role, token)
 	fmt.Printf("Valid for 5 minutes\n")
 }
 
@@ -152,9 +285,86 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleStats(w http.ResponseWriter, r *http.Request, broker *broker.Broker) {
-	stats := broker.GetStats()
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	fmt.Fprintf(w, `{"stats":%v,"timestamp":"%s"}`, stats, time.Now().UTC().Format(time.RFC3339))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stats":     broker.GetStats(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// hostIDFromSessionID recovers the hostID half of a sessionID minted as
+// "<hostID>_<unixnano>" (see broker's handleHostRegister), splitting on the
+// last underscore since hostID itself may contain one.
+func hostIDFromSessionID(sessionID string) (string, bool) {
+	idx := strings.LastIndex(sessionID, "_")
+	if idx <= 0 || idx == len(sessionID)-1 {
+		return "", false
+	}
+	return sessionID[:idx], true
+}
+
+// handleSessionReplay streams a recorded session back at /sessions/{id}.
+// The optional ?speed= query param paces playback server-side; speed=2
+// plays twice as fast as the original recording. A recording holds raw
+// terminal I/O - anything the PTY produced, passwords and API keys typed
+// at a prompt included - and sessionID's hostID half is often guessable,
+// so the caller must present a connect token for that host via
+// "Authorization: Bearer <token>", the same token type /ws validates.
+func handleSessionReplay(w http.ResponseWriter, r *http.Request, store recorder.Store, jwtManager *auth.JWTManager, devices registry.DeviceRegistry) {
+	sessionID := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	if sessionID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	hostID, ok := hostIDFromSessionID(sessionID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	const bearerPrefix = "Bearer "
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, bearerPrefix) {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	claims, err := jwtManager.ValidateConnectToken(strings.TrimPrefix(authHeader, bearerPrefix))
+	if err != nil {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	if claims.HostID != hostID || !devices.Authorized(hostID, claims.DeviceKey) {
+		http.Error(w, "not authorized for this session", http.StatusForbidden)
+		return
+	}
+
+	speed := 1.0
+	if s := r.URL.Query().Get("speed"); s != "" {
+		if parsed, err := strconv.ParseFloat(s, 64); err == nil && parsed > 0 {
+			speed = parsed
+		}
+	}
+
+	f, err := store.Open(sessionID)
+	if err != nil {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	var flush func()
+	if flusher != nil {
+		flush = flusher.Flush
+	}
+
+	if err := recorder.Replay(w, f, speed, flush); err != nil {
+		logrus.WithError(err).WithField("sessionID", sessionID).Error("Failed to replay session")
+	}
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+// Command rtx-replay renders a recorded session (asciicast v2) to the
+// local terminal, pacing output the same way the relay's /sessions/{id}
+// endpoint does.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rtx/relay/internal/recorder"
+)
+
+func main() {
+	speed := flag.Float64("speed", 1.0, "Playback speed multiplier")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: rtx-replay [-speed N] <recording-file>")
+		os.Exit(1)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open recording: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := recorder.Replay(os.Stdout, f, *speed, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -13,9 +13,25 @@ type JWTManager struct {
 	secret []byte
 }
 
+// Role identifies what a client connect token authorizes its holder to do
+// once connected to a host's session.
+type Role string
+
+const (
+	// RoleController may send input to the host and is the only role that
+	// can hold exclusive control of a session at a time.
+	RoleController Role = "controller"
+	// RoleViewer receives host output read-only; its input is dropped.
+	RoleViewer Role = "viewer"
+	// RoleRecorder receives host output read-only for the purpose of
+	// recording a session; its input is dropped.
+	RoleRecorder Role = "recorder"
+)
+
 type ConnectTokenClaims struct {
 	HostID    string `json:"host_id"`
 	DeviceKey string `json:"device_key"`
+	Role      Role   `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -25,12 +41,20 @@ func NewJWTManager(secret string) *JWTManager {
 	}
 }
 
-// GenerateConnectToken creates a short-lived JWT for client connection
+// GenerateConnectToken creates a short-lived JWT for a host registration.
 func (j *JWTManager) GenerateConnectToken(hostID, deviceKey string) (string, error) {
+	return j.GenerateClientToken(hostID, deviceKey, RoleController)
+}
+
+// GenerateClientToken creates a short-lived JWT for a client connection,
+// binding the connection to the given role for the lifetime of the token.
+func (j *JWTManager) GenerateClientToken(hostID, deviceKey string, role Role) (string, error) {
 	claims := ConnectTokenClaims{
 		HostID:    hostID,
 		DeviceKey: deviceKey,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newJTI(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "rtx-relay",
@@ -67,4 +91,12 @@ func GenerateRandomKey() string {
 	bytes := make([]byte, 32)
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
+}
+
+// newJTI generates the random token ID (jti claim) used to correlate a
+// single connect token across audit log lines.
+func newJTI() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
 }
\ No newline at end of file
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateConnectTokenRoundTrip(t *testing.T) {
+	jm := NewJWTManager("test-secret")
+
+	token, err := jm.GenerateClientToken("host-1", "device-1", RoleViewer)
+	if err != nil {
+		t.Fatalf("GenerateClientToken: %v", err)
+	}
+
+	claims, err := jm.ValidateConnectToken(token)
+	if err != nil {
+		t.Fatalf("ValidateConnectToken: %v", err)
+	}
+	if claims.HostID != "host-1" {
+		t.Errorf("HostID = %q, want %q", claims.HostID, "host-1")
+	}
+	if claims.DeviceKey != "device-1" {
+		t.Errorf("DeviceKey = %q, want %q", claims.DeviceKey, "device-1")
+	}
+	if claims.Role != RoleViewer {
+		t.Errorf("Role = %q, want %q", claims.Role, RoleViewer)
+	}
+	if claims.ID == "" {
+		t.Error("ID (jti) = \"\", want non-empty")
+	}
+}
+
+func TestValidateConnectTokenRejectsWrongSecret(t *testing.T) {
+	token, err := NewJWTManager("secret-a").GenerateConnectToken("host-1", "device-1")
+	if err != nil {
+		t.Fatalf("GenerateConnectToken: %v", err)
+	}
+
+	if _, err := NewJWTManager("secret-b").ValidateConnectToken(token); err == nil {
+		t.Error("ValidateConnectToken with the wrong secret = nil error, want error")
+	}
+}
+
+func TestValidateConnectTokenRejectsUnsignedAlg(t *testing.T) {
+	// A token signed with alg "none" must never validate, regardless of
+	// secret - accepting it would let an attacker forge claims outright.
+	claims := ConnectTokenClaims{
+		HostID:    "host-1",
+		DeviceKey: "device-1",
+		Role:      RoleController,
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("signing unsigned token: %v", err)
+	}
+
+	if _, err := NewJWTManager("test-secret").ValidateConnectToken(unsigned); err == nil {
+		t.Error("ValidateConnectToken accepted an alg=none token, want error")
+	}
+}
+
+func TestValidateConnectTokenRejectsGarbage(t *testing.T) {
+	if _, err := NewJWTManager("test-secret").ValidateConnectToken("not.a.token"); err == nil {
+		t.Error("ValidateConnectToken accepted a garbage string, want error")
+	}
+}
+
+func TestGenerateConnectTokenIsController(t *testing.T) {
+	jm := NewJWTManager("test-secret")
+
+	token, err := jm.GenerateConnectToken("host-1", "device-1")
+	if err != nil {
+		t.Fatalf("GenerateConnectToken: %v", err)
+	}
+
+	claims, err := jm.ValidateConnectToken(token)
+	if err != nil {
+		t.Fatalf("ValidateConnectToken: %v", err)
+	}
+	if claims.Role != RoleController {
+		t.Errorf("Role = %q, want %q (host registration implies controller)", claims.Role, RoleController)
+	}
+}
+
+func TestNewJTIIsRandomAndHex(t *testing.T) {
+	a := newJTI()
+	b := newJTI()
+	if a == b {
+		t.Fatal("newJTI produced the same value twice, want unique per call")
+	}
+	if strings.ContainsFunc(a, func(r rune) bool {
+		return !strings.ContainsRune("0123456789abcdef", r)
+	}) {
+		t.Errorf("newJTI() = %q, want hex-only characters", a)
+	}
+}
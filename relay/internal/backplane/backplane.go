@@ -0,0 +1,51 @@
+// Package backplane lets multiple relay processes share host presence and
+// forward terminal frames between each other, so a host registered on one
+// node can be reached by a client that connected to another. A presence
+// record maps a hostID to the node currently hosting it and expires after
+// a TTL unless refreshed by a heartbeat; frame forwarding between nodes
+// rides per-session pub/sub subjects. Redis and NATS implementations are
+// provided; broker.Broker only depends on the Backplane interface.
+package backplane
+
+import "time"
+
+// Direction values for SessionSubject: "up" carries client input toward
+// the node hosting the session, "down" carries host output toward the
+// node serving the client.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// Backplane is implemented by the Redis and NATS backends in this package.
+// The presence methods back Broker's cluster-wide hostID -> node lookup;
+// Publish/Subscribe carry forwarded frames between nodes over
+// SessionSubject.
+type Backplane interface {
+	// Announce records that hostID is owned by this node (encoded into
+	// value) until ttl elapses, refreshing any existing record.
+	Announce(hostID, value string, ttl time.Duration) error
+	// Lookup returns the value last Announce'd for hostID, if its record
+	// hasn't expired. ok is false if there is no live record.
+	Lookup(hostID string) (value string, ok bool, err error)
+	// Withdraw removes hostID's presence record immediately, so a cleanly
+	// disconnected host doesn't keep routing clients to it until the TTL
+	// expires on its own.
+	Withdraw(hostID string) error
+
+	// Publish sends data to every node currently subscribed to subject.
+	Publish(subject string, data []byte) error
+	// Subscribe delivers every message published to subject to handler,
+	// until the returned unsubscribe func is called. handler is invoked
+	// from a backend-owned goroutine.
+	Subscribe(subject string, handler func(data []byte)) (unsubscribe func() error, err error)
+
+	// Close releases the backend connection.
+	Close() error
+}
+
+// SessionSubject returns the pub/sub subject node-to-node frame forwarding
+// uses for sessionID, suffixed by direction (DirectionUp/DirectionDown).
+func SessionSubject(sessionID, direction string) string {
+	return "rtx.session." + sessionID + "." + direction
+}
@@ -0,0 +1,94 @@
+package backplane
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// presenceBucket is the JetStream key-value bucket presence records live
+// in. NATS KV expiry is bucket-wide rather than per-key, so every relay
+// process sharing a NATS cluster must agree on the presenceTTL passed to
+// NewNATS.
+const presenceBucket = "rtx_presence"
+
+// NATS is a Backplane backed by a NATS server: presence records live in a
+// JetStream key-value bucket with a TTL, and forwarding rides core NATS
+// pub/sub subjects.
+type NATS struct {
+	conn *nats.Conn
+	kv   nats.KeyValue
+}
+
+// NewNATS returns a Backplane using conn, creating (or reusing) the
+// presence bucket with the given TTL.
+func NewNATS(conn *nats.Conn, presenceTTL time.Duration) (*NATS, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("backplane: jetstream context: %w", err)
+	}
+
+	kv, err := js.KeyValue(presenceBucket)
+	if err != nil {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket: presenceBucket,
+			TTL:    presenceTTL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("backplane: create presence bucket: %w", err)
+		}
+	}
+
+	return &NATS{conn: conn, kv: kv}, nil
+}
+
+// Announce implements Backplane by writing hostID's presence entry; ttl is
+// ignored since NATS KV expiry is bucket-wide (set in NewNATS).
+func (n *NATS) Announce(hostID, value string, ttl time.Duration) error {
+	_, err := n.kv.Put(hostID, []byte(value))
+	return err
+}
+
+// Lookup implements Backplane via a KV Get.
+func (n *NATS) Lookup(hostID string) (string, bool, error) {
+	entry, err := n.kv.Get(hostID)
+	if err == nats.ErrKeyNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(entry.Value()), true, nil
+}
+
+// Withdraw implements Backplane via a KV Delete.
+func (n *NATS) Withdraw(hostID string) error {
+	err := n.kv.Delete(hostID)
+	if err == nats.ErrKeyNotFound {
+		return nil
+	}
+	return err
+}
+
+// Publish implements Backplane via a core NATS publish.
+func (n *NATS) Publish(subject string, data []byte) error {
+	return n.conn.Publish(subject, data)
+}
+
+// Subscribe implements Backplane via a core NATS subscription.
+func (n *NATS) Subscribe(subject string, handler func(data []byte)) (func() error, error) {
+	sub, err := n.conn.Subscribe(subject, func(msg *nats.Msg) {
+		handler(msg.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
+
+// Close closes the underlying NATS connection.
+func (n *NATS) Close() error {
+	n.conn.Close()
+	return nil
+}
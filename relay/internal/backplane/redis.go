@@ -0,0 +1,76 @@
+package backplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Backplane backed by a Redis server: presence records are
+// plain SETEX keys, and forwarding rides Redis pub/sub.
+type Redis struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+// NewRedis returns a Backplane using client for both presence keys and
+// pub/sub. Close also closes client.
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client, ctx: context.Background()}
+}
+
+func presenceKey(hostID string) string {
+	return "rtx:presence:" + hostID
+}
+
+// Announce implements Backplane via SETEX.
+func (r *Redis) Announce(hostID, value string, ttl time.Duration) error {
+	return r.client.SetEx(r.ctx, presenceKey(hostID), value, ttl).Err()
+}
+
+// Lookup implements Backplane via GET.
+func (r *Redis) Lookup(hostID string) (string, bool, error) {
+	value, err := r.client.Get(r.ctx, presenceKey(hostID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Withdraw implements Backplane via DEL.
+func (r *Redis) Withdraw(hostID string) error {
+	return r.client.Del(r.ctx, presenceKey(hostID)).Err()
+}
+
+// Publish implements Backplane via PUBLISH.
+func (r *Redis) Publish(subject string, data []byte) error {
+	return r.client.Publish(r.ctx, subject, data).Err()
+}
+
+// Subscribe implements Backplane via Redis pub/sub.
+func (r *Redis) Subscribe(subject string, handler func(data []byte)) (func() error, error) {
+	sub := r.client.Subscribe(r.ctx, subject)
+	if _, err := sub.Receive(r.ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	ch := sub.Channel()
+	go func() {
+		for msg := range ch {
+			handler([]byte(msg.Payload))
+		}
+	}()
+
+	return sub.Close, nil
+}
+
+// Close closes the underlying Redis client.
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
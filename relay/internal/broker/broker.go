@@ -1,19 +1,52 @@
 package broker
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
+
+	"github.com/rtx/relay/internal/auth"
+	"github.com/rtx/relay/internal/backplane"
+	"github.com/rtx/relay/internal/metrics"
+	"github.com/rtx/relay/internal/ratelimit"
+	"github.com/rtx/relay/internal/recorder"
+	"github.com/rtx/relay/internal/registry"
+)
+
+// CloseAuthFailed is the WebSocket close code sent when a host or client
+// fails JWT or device-key validation. It is in the private-use range
+// (4000-4999) reserved for application-specific close codes.
+const CloseAuthFailed = 4401
+
+// CloseRateLimited is the WebSocket close code sent when a connection
+// exceeds its configured byte/message rate limit.
+const CloseRateLimited = 4429
+
+// defaultReadLimitBytes is used when Limits.ReadLimitBytes is unset; the
+// previous hardcoded 512-byte limit dropped any real terminal payload.
+const defaultReadLimitBytes = 1 << 20 // 1 MiB
+
+// defaultSendDeadline is used when Limits.SendDeadline is unset.
+const defaultSendDeadline = 5 * time.Second
+
+// defaultPresenceTTL and defaultHeartbeatInterval are used when a
+// ClusterConfig doesn't set them explicitly.
+const (
+	defaultPresenceTTL       = 15 * time.Second
+	defaultHeartbeatInterval = 5 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    subprotocols,
 	CheckOrigin: func(r *http.Request) bool {
 		// Allow all origins for development - restrict in production
 		return true
@@ -23,21 +56,28 @@ var upgrader = websocket.Upgrader{
 type MessageType string
 
 const (
-	MsgHostRegister   MessageType = "host_register"
-	MsgClientConnect  MessageType = "client_connect"
-	MsgHostRegistered MessageType = "host_registered"
-	MsgClientReady    MessageType = "client_ready"
-	MsgForward        MessageType = "forward"
-	MsgPing           MessageType = "ping"
-	MsgPong           MessageType = "pong"
+	MsgHostRegister       MessageType = "host_register"
+	MsgClientConnect      MessageType = "client_connect"
+	MsgHostRegistered     MessageType = "host_registered"
+	MsgClientReady        MessageType = "client_ready"
+	MsgForward            MessageType = "forward"
+	MsgPing               MessageType = "ping"
+	MsgPong               MessageType = "pong"
+	MsgControllerTakeover MessageType = "controller_takeover"
 )
 
 type Message struct {
-	Type      MessageType     `json:"type"`
-	HostID    string          `json:"host_id,omitempty"`
-	Token     string          `json:"token,omitempty"`
-	Data      json.RawMessage `json:"data,omitempty"`
-	Timestamp time.Time       `json:"timestamp"`
+	Type   MessageType `json:"type"`
+	HostID string      `json:"host_id,omitempty"`
+	Token  string      `json:"token,omitempty"`
+	// Data carries arbitrary terminal bytes (escape sequences, NULs,
+	// anything a PTY produces), not necessarily valid JSON text, so it's a
+	// plain []byte rather than json.RawMessage: encoding/json marshals a
+	// []byte field as a base64 string and unmarshals it back losslessly,
+	// whereas json.RawMessage requires the bytes to already be valid JSON
+	// and corrupts (or fails to encode) real output.
+	Data      []byte    `json:"data,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type Connection struct {
@@ -46,22 +86,233 @@ type Connection struct {
 	hostID   string
 	isHost   bool
 	clientID string
+	role     auth.Role
+
+	// sessionID identifies a host's recording session; set on the host
+	// connection when it registers and used as the recorder key for every
+	// frame forwarded to its clients.
+	sessionID string
+
+	// peerCert is the client's leaf certificate when the connection was
+	// established over mutual TLS; nil if mTLS is not in use.
+	peerCert *x509.Certificate
+
+	remoteAddr  string
+	connectedAt time.Time
+
+	// binary is true once the connection negotiated BinarySubprotocol at
+	// upgrade time; frames are then encoded/decoded via encodeBinary /
+	// decodeBinary instead of WriteJSON/ReadJSON.
+	binary  bool
+	limiter *ratelimit.Limiter
+
+	// clusterUnsub, when set, tears down this connection's backplane
+	// subscription (a host's "up" subject, or a remote client's "down"
+	// subject) and is called once during cleanup.
+	clusterUnsub func() error
+
+	// stopHeartbeat is set on a host connection in cluster mode; closing
+	// it stops the goroutine that periodically refreshes the host's
+	// presence record.
+	stopHeartbeat chan struct{}
+
+	// remoteSessionID is set on a client connection when its host is owned
+	// by a different cluster node: it's the session key used to publish
+	// input frames to that node's "up" subject instead of forwarding
+	// through a local chan Message.
+	remoteSessionID string
+
+	// sendMu guards send and closed so sendMessage and cleanup can never
+	// race: without it, a send already in flight on send when cleanup
+	// closes it (e.g. a controller takeover force-closing the displaced
+	// connection while a takeover notice is still being delivered to it)
+	// panics with "send on closed channel". Every enqueue onto send and
+	// the close of send itself must hold sendMu and check closed first.
+	sendMu sync.Mutex
+	closed bool
 }
 
 type Broker struct {
 	hosts   map[string]*Connection // hostID -> host connection
 	clients map[string]*Connection // clientID -> client connection
-	hostClients map[string]string   // hostID -> clientID (1:1 mapping)
-	mu      sync.RWMutex
-	logger  *logrus.Logger
+	// hostClients holds every client attached to a host, keyed by clientID.
+	// At most one of them may have role auth.RoleController at a time;
+	// the rest are viewers/recorders receiving host output read-only.
+	hostClients map[string]map[string]*Connection
+	mu          sync.RWMutex
+	logger      *logrus.Logger
+	// audit receives one structured (JSON) line per host register, client
+	// connect, auth failure, and disconnect, for correlation with external
+	// audit tooling. May be the same logger as logger.
+	audit *logrus.Logger
+
+	jwtManager *auth.JWTManager
+	devices    registry.DeviceRegistry
+
+	recorder    recorder.Recorder
+	recordHosts map[string]bool // hostID -> recording enabled
+
+	limits  Limits
+	cluster *ClusterConfig
+}
+
+// Limits bundles the per-connection framing and throughput knobs that grew
+// too numerous for NewBroker's parameter list: the WebSocket read limit,
+// the rate limiter's byte/message budgets, and how long sendMessage will
+// apply backpressure before giving up on a slow connection. A zero Limits
+// falls back to defaultReadLimitBytes/defaultSendDeadline and disables
+// rate limiting.
+type Limits struct {
+	ReadLimitBytes int64
+	BytesPerSec    float64
+	MsgsPerSec     float64
+	SendDeadline   time.Duration
 }
 
-func NewBroker(logger *logrus.Logger) *Broker {
+// ClusterConfig enables multi-node operation. When set, a host registered
+// on this node announces its presence (this node's ID and session ID) via
+// Backplane, refreshed by a heartbeat until the host disconnects; a client
+// connecting to a host owned by another node is attached via the
+// backplane's pub/sub (see SessionSubject) instead of a local chan
+// Message. PresenceTTL and HeartbeatInterval default to
+// defaultPresenceTTL/defaultHeartbeatInterval when zero.
+type ClusterConfig struct {
+	Backplane         backplane.Backplane
+	NodeID            string
+	PresenceTTL       time.Duration
+	HeartbeatInterval time.Duration
+}
+
+// NewBroker constructs a Broker. jwtManager validates connect tokens on
+// every host-register and client-connect message; devices authorizes the
+// device key bound to a host before registration is allowed. rec and
+// recordHosts may be nil, in which case no session is recorded; otherwise
+// only hosts present (and true) in recordHosts are teed to rec. audit
+// receives the structured audit trail described on the Broker.audit field.
+// cluster may be nil, in which case the broker only ever sees hosts and
+// clients connected directly to it.
+func NewBroker(logger *logrus.Logger, audit *logrus.Logger, jwtManager *auth.JWTManager, devices registry.DeviceRegistry, rec recorder.Recorder, recordHosts map[string]bool, limits Limits, cluster *ClusterConfig) *Broker {
+	if limits.ReadLimitBytes <= 0 {
+		limits.ReadLimitBytes = defaultReadLimitBytes
+	}
+	if limits.SendDeadline <= 0 {
+		limits.SendDeadline = defaultSendDeadline
+	}
+	if cluster != nil {
+		if cluster.PresenceTTL <= 0 {
+			cluster.PresenceTTL = defaultPresenceTTL
+		}
+		if cluster.HeartbeatInterval <= 0 {
+			cluster.HeartbeatInterval = defaultHeartbeatInterval
+		}
+	}
+
 	return &Broker{
 		hosts:       make(map[string]*Connection),
 		clients:     make(map[string]*Connection),
-		hostClients: make(map[string]string),
+		hostClients: make(map[string]map[string]*Connection),
 		logger:      logger,
+		audit:       audit,
+		jwtManager:  jwtManager,
+		devices:     devices,
+		recorder:    rec,
+		recordHosts: recordHosts,
+		limits:      limits,
+		cluster:     cluster,
+	}
+}
+
+// auditEvent emits a structured audit log line for event, merging in the
+// connection's remote address and the given fields.
+func (b *Broker) auditEvent(event string, conn *Connection, fields logrus.Fields) {
+	merged := logrus.Fields{
+		"event":       event,
+		"remote_addr": conn.remoteAddr,
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	b.audit.WithFields(merged).Info("audit")
+}
+
+// recordingEnabled reports whether hostID is configured for session
+// recording.
+func (b *Broker) recordingEnabled(hostID string) bool {
+	return b.recorder != nil && b.recordHosts[hostID]
+}
+
+// controllerOf returns the current controller connection for hostID, if
+// any. Callers must hold b.mu.
+func (b *Broker) controllerOf(hostID string) *Connection {
+	for _, client := range b.hostClients[hostID] {
+		if client.role == auth.RoleController {
+			return client
+		}
+	}
+	return nil
+}
+
+// presenceValue and parsePresenceValue encode/decode the string stored in
+// a cluster presence record: the owning node's ID and its local session
+// ID for the host, joined by "|" (neither contains one: NodeID is operator
+// configured, sessionID is "<hostID>_<unixnano>").
+func presenceValue(nodeID, sessionID string) string {
+	return nodeID + "|" + sessionID
+}
+
+func parsePresenceValue(value string) (nodeID, sessionID string, ok bool) {
+	parts := strings.SplitN(value, "|", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// announceHost starts cluster presence for a newly registered local host:
+// it publishes the initial presence record, subscribes to the session's
+// "up" subject so remote clients' input reaches this host, and spawns a
+// heartbeat goroutine that refreshes the record until the host
+// disconnects.
+func (b *Broker) announceHost(conn *Connection) {
+	value := presenceValue(b.cluster.NodeID, conn.sessionID)
+	if err := b.cluster.Backplane.Announce(conn.hostID, value, b.cluster.PresenceTTL); err != nil {
+		b.logger.WithError(err).WithField("hostID", conn.hostID).Error("Failed to announce host presence")
+	}
+
+	unsub, err := b.cluster.Backplane.Subscribe(backplane.SessionSubject(conn.sessionID, backplane.DirectionUp), func(data []byte) {
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			b.logger.WithError(err).Error("Failed to decode cluster frame")
+			return
+		}
+		b.sendMessage(conn, msg)
+	})
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", conn.hostID).Error("Failed to subscribe to cluster session subject")
+	} else {
+		conn.clusterUnsub = unsub
+	}
+
+	conn.stopHeartbeat = make(chan struct{})
+	go b.heartbeatHost(conn)
+}
+
+// heartbeatHost periodically re-announces conn's presence record until
+// conn.stopHeartbeat is closed in cleanup.
+func (b *Broker) heartbeatHost(conn *Connection) {
+	ticker := time.NewTicker(b.cluster.HeartbeatInterval)
+	defer ticker.Stop()
+
+	value := presenceValue(b.cluster.NodeID, conn.sessionID)
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.cluster.Backplane.Announce(conn.hostID, value, b.cluster.PresenceTTL); err != nil {
+				b.logger.WithError(err).WithField("hostID", conn.hostID).Warn("Failed to refresh host presence")
+			}
+		case <-conn.stopHeartbeat:
+			return
+		}
 	}
 }
 
@@ -73,8 +324,17 @@ func (b *Broker) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	connection := &Connection{
-		conn: conn,
-		send: make(chan Message, 256),
+		conn:        conn,
+		send:        make(chan Message, 256),
+		remoteAddr:  r.RemoteAddr,
+		connectedAt: time.Now(),
+		binary:      conn.Subprotocol() == BinarySubprotocol,
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		connection.peerCert = r.TLS.PeerCertificates[0]
+	}
+	if b.limits.BytesPerSec > 0 || b.limits.MsgsPerSec > 0 {
+		connection.limiter = ratelimit.NewLimiter(b.limits.BytesPerSec, b.limits.MsgsPerSec)
 	}
 
 	go b.handleConnection(connection)
@@ -87,7 +347,7 @@ func (b *Broker) handleConnection(conn *Connection) {
 	}()
 
 	// Set up ping/pong for connection health
-	conn.conn.SetReadLimit(512)
+	conn.conn.SetReadLimit(b.limits.ReadLimitBytes)
 	conn.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.conn.SetPongHandler(func(string) error {
 		conn.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -99,8 +359,7 @@ func (b *Broker) handleConnection(conn *Connection) {
 
 	// Handle incoming messages
 	for {
-		var msg Message
-		err := conn.conn.ReadJSON(&msg)
+		msg, n, err := b.readMessage(conn)
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				b.logger.WithError(err).Error("WebSocket error")
@@ -108,11 +367,45 @@ func (b *Broker) handleConnection(conn *Connection) {
 			break
 		}
 
+		if conn.limiter != nil && !conn.limiter.Allow(n) {
+			b.logger.WithField("remoteAddr", conn.remoteAddr).Warn("Connection exceeded rate limit, closing")
+			closeMsg := websocket.FormatCloseMessage(CloseRateLimited, "rate limit exceeded")
+			conn.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			break
+		}
+
 		msg.Timestamp = time.Now()
 		b.handleMessage(conn, msg)
 	}
 }
 
+// readMessage reads one frame off conn, decoding it as binary or JSON
+// depending on the negotiated subprotocol, and returns the frame's raw
+// byte size for rate limiting.
+func (b *Broker) readMessage(conn *Connection) (Message, int, error) {
+	wsType, data, err := conn.conn.ReadMessage()
+	if err != nil {
+		return Message{}, 0, err
+	}
+
+	if conn.binary {
+		if wsType != websocket.BinaryMessage {
+			return Message{}, 0, fmt.Errorf("expected binary frame, got websocket message type %d", wsType)
+		}
+		msg, err := decodeBinary(data)
+		if err != nil {
+			return Message{}, 0, err
+		}
+		return msg, len(data), nil
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, 0, err
+	}
+	return msg, len(data), nil
+}
+
 func (b *Broker) writer(conn *Connection) {
 	ticker := time.NewTicker(54 * time.Second)
 	defer ticker.Stop()
@@ -126,7 +419,7 @@ func (b *Broker) writer(conn *Connection) {
 				return
 			}
 
-			if err := conn.conn.WriteJSON(msg); err != nil {
+			if err := b.writeMessage(conn, msg); err != nil {
 				b.logger.WithError(err).Error("Failed to write message")
 				return
 			}
@@ -140,6 +433,18 @@ func (b *Broker) writer(conn *Connection) {
 	}
 }
 
+// writeMessage writes msg to conn using the negotiated framing.
+func (b *Broker) writeMessage(conn *Connection, msg Message) error {
+	if !conn.binary {
+		return conn.conn.WriteJSON(msg)
+	}
+	data, err := encodeBinary(msg)
+	if err != nil {
+		return err
+	}
+	return conn.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
 func (b *Broker) handleMessage(conn *Connection, msg Message) {
 	switch msg.Type {
 	case MsgHostRegister:
@@ -161,15 +466,46 @@ func (b *Broker) handleHostRegister(conn *Connection, msg Message) {
 		return
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	claims, err := b.jwtManager.ValidateConnectToken(msg.Token)
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", msg.HostID).Warn("Host register token validation failed")
+		b.rejectConnection(conn, msg.HostID, "", "invalid_token")
+		return
+	}
+	if claims.HostID != msg.HostID {
+		b.logger.WithFields(logrus.Fields{
+			"hostID":      msg.HostID,
+			"claimHostID": claims.HostID,
+		}).Warn("Host register hostID does not match token claim")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_id_mismatch")
+		return
+	}
+	if !b.devices.Authorized(msg.HostID, claims.DeviceKey) {
+		b.logger.WithField("hostID", msg.HostID).Warn("Host register device key not authorized")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "device_key_unauthorized")
+		return
+	}
+	if conn.peerCert != nil && !certMatchesHostID(conn.peerCert, msg.HostID) {
+		b.logger.WithField("hostID", msg.HostID).Warn("Host register certificate does not match hostID")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "cert_hostid_mismatch")
+		return
+	}
 
 	// Register host
 	conn.hostID = msg.HostID
 	conn.isHost = true
+	conn.sessionID = fmt.Sprintf("%s_%d", msg.HostID, time.Now().UnixNano())
+
+	b.mu.Lock()
 	b.hosts[msg.HostID] = conn
+	metrics.Hosts.Set(float64(len(b.hosts)))
+	b.mu.Unlock()
 
 	b.logger.WithField("hostID", msg.HostID).Info("Host registered")
+	b.auditEvent("host_register", conn, logrus.Fields{
+		"host_id": msg.HostID,
+		"jti":     claims.ID,
+	})
 
 	// Send confirmation
 	b.sendMessage(conn, Message{
@@ -177,6 +513,10 @@ func (b *Broker) handleHostRegister(conn *Connection, msg Message) {
 		HostID:    msg.HostID,
 		Timestamp: time.Now(),
 	})
+
+	if b.cluster != nil {
+		b.announceHost(conn)
+	}
 }
 
 func (b *Broker) handleClientConnect(conn *Connection, msg Message) {
@@ -186,25 +526,56 @@ func (b *Broker) handleClientConnect(conn *Connection, msg Message) {
 		return
 	}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	claims, err := b.jwtManager.ValidateConnectToken(msg.Token)
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", msg.HostID).Warn("Client connect token validation failed")
+		b.rejectConnection(conn, msg.HostID, "", "invalid_token")
+		return
+	}
+	if claims.HostID != msg.HostID {
+		b.logger.WithFields(logrus.Fields{
+			"hostID":      msg.HostID,
+			"claimHostID": claims.HostID,
+		}).Warn("Client connect hostID does not match token claim")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_id_mismatch")
+		return
+	}
+	if !b.devices.Authorized(msg.HostID, claims.DeviceKey) {
+		b.logger.WithField("hostID", msg.HostID).Warn("Client connect device key not authorized")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "device_key_unauthorized")
+		return
+	}
 
-	// Check if host exists
+	b.mu.Lock()
 	host, exists := b.hosts[msg.HostID]
 	if !exists {
+		b.mu.Unlock()
+		if b.cluster != nil {
+			b.handleRemoteClientConnect(conn, msg, claims)
+			return
+		}
 		b.logger.WithField("hostID", msg.HostID).Error("Host not found for client connection")
-		conn.conn.Close()
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_not_found")
 		return
 	}
 
-	// Check if host already has a client
-	if existingClientID, hasClient := b.hostClients[msg.HostID]; hasClient {
-		b.logger.WithField("hostID", msg.HostID).Warn("Host already has a client, disconnecting existing")
-		if existingClient, exists := b.clients[existingClientID]; exists {
-			existingClient.conn.Close()
-			delete(b.clients, existingClientID)
+	role := claims.Role
+	if role == "" {
+		role = auth.RoleViewer
+	}
+
+	// Only one controller may hold a session at a time; a new controller
+	// takes over and the previous one is notified and disconnected. The
+	// takeover notice is sent after b.mu is released below, since
+	// sendMessage must never be called while holding it (see sendMessage).
+	var displaced *Connection
+	if role == auth.RoleController {
+		if existing := b.controllerOf(msg.HostID); existing != nil {
+			displaced = existing
+			existing.conn.Close()
+			delete(b.clients, existing.clientID)
+			delete(b.hostClients[msg.HostID], existing.clientID)
 		}
-		delete(b.hostClients, msg.HostID)
 	}
 
 	// Generate client ID and register
@@ -212,13 +583,35 @@ func (b *Broker) handleClientConnect(conn *Connection, msg Message) {
 	conn.clientID = clientID
 	conn.hostID = msg.HostID
 	conn.isHost = false
+	conn.role = role
 	b.clients[clientID] = conn
-	b.hostClients[msg.HostID] = clientID
+	if b.hostClients[msg.HostID] == nil {
+		b.hostClients[msg.HostID] = make(map[string]*Connection)
+	}
+	b.hostClients[msg.HostID][clientID] = conn
+	metrics.Clients.Set(float64(len(b.clients)))
+	b.mu.Unlock()
+
+	if displaced != nil {
+		b.logger.WithField("hostID", msg.HostID).Warn("Controller takeover, disconnecting previous controller")
+		b.sendMessage(displaced, Message{
+			Type:      MsgControllerTakeover,
+			HostID:    msg.HostID,
+			Timestamp: time.Now(),
+		})
+	}
 
 	b.logger.WithFields(logrus.Fields{
 		"clientID": clientID,
 		"hostID":   msg.HostID,
+		"role":     role,
 	}).Info("Client connected")
+	b.auditEvent("client_connect", conn, logrus.Fields{
+		"host_id":   msg.HostID,
+		"client_id": clientID,
+		"role":      role,
+		"jti":       claims.ID,
+	})
 
 	// Notify both host and client that connection is ready
 	b.sendMessage(host, Message{
@@ -234,65 +627,351 @@ func (b *Broker) handleClientConnect(conn *Connection, msg Message) {
 	})
 }
 
-func (b *Broker) forwardMessage(from *Connection, msg Message) {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+// handleRemoteClientConnect attaches a client to a host owned by a
+// different cluster node: it looks up the host's presence record via the
+// backplane and, if live, subscribes this client to the session's "down"
+// subject (host output) and marks it to publish input frames to the "up"
+// subject instead of forwarding through a local chan Message. Controller
+// takeover is only coordinated among clients attached to the same node;
+// a takeover against a controller connected through another node is not
+// detected here.
+func (b *Broker) handleRemoteClientConnect(conn *Connection, msg Message, claims *auth.ConnectTokenClaims) {
+	value, ok, err := b.cluster.Backplane.Lookup(msg.HostID)
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", msg.HostID).Error("Failed to look up host presence")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_not_found")
+		return
+	}
+	if !ok {
+		b.logger.WithField("hostID", msg.HostID).Warn("Host not found locally or in cluster presence")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_not_found")
+		return
+	}
+	_, sessionID, valid := parsePresenceValue(value)
+	if !valid {
+		b.logger.WithField("hostID", msg.HostID).Error("Malformed cluster presence record")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_not_found")
+		return
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = auth.RoleViewer
+	}
 
+	clientID := fmt.Sprintf("client_%d", time.Now().UnixNano())
+	conn.clientID = clientID
+	conn.hostID = msg.HostID
+	conn.isHost = false
+	conn.role = role
+	conn.remoteSessionID = sessionID
+
+	unsub, err := b.cluster.Backplane.Subscribe(backplane.SessionSubject(sessionID, backplane.DirectionDown), func(data []byte) {
+		var hostMsg Message
+		if err := json.Unmarshal(data, &hostMsg); err != nil {
+			b.logger.WithError(err).Error("Failed to decode cluster frame")
+			return
+		}
+		b.sendMessage(conn, hostMsg)
+	})
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", msg.HostID).Error("Failed to subscribe to cluster session subject")
+		b.rejectConnection(conn, msg.HostID, claims.ID, "host_not_found")
+		return
+	}
+	conn.clusterUnsub = unsub
+
+	b.mu.Lock()
+	var displaced *Connection
+	if role == auth.RoleController {
+		if existing := b.controllerOf(msg.HostID); existing != nil {
+			displaced = existing
+			existing.conn.Close()
+			delete(b.clients, existing.clientID)
+			delete(b.hostClients[msg.HostID], existing.clientID)
+		}
+	}
+	b.clients[clientID] = conn
+	if b.hostClients[msg.HostID] == nil {
+		b.hostClients[msg.HostID] = make(map[string]*Connection)
+	}
+	b.hostClients[msg.HostID][clientID] = conn
+	metrics.Clients.Set(float64(len(b.clients)))
+	b.mu.Unlock()
+
+	if displaced != nil {
+		b.logger.WithField("hostID", msg.HostID).Warn("Controller takeover, disconnecting previous controller")
+		b.sendMessage(displaced, Message{Type: MsgControllerTakeover, HostID: msg.HostID, Timestamp: time.Now()})
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"clientID": clientID,
+		"hostID":   msg.HostID,
+		"role":     role,
+	}).Info("Client connected to remote host")
+	b.auditEvent("client_connect", conn, logrus.Fields{
+		"host_id":   msg.HostID,
+		"client_id": clientID,
+		"role":      role,
+		"jti":       claims.ID,
+	})
+
+	b.sendMessage(conn, Message{
+		Type:      MsgClientReady,
+		HostID:    msg.HostID,
+		Timestamp: time.Now(),
+	})
+}
+
+// forwardMessage routes msg from a host to its attached clients or from a
+// controller client to its host. It never calls sendMessage while holding
+// b.mu: sendMessage can block for up to limits.SendDeadline and, on
+// timeout, calls cleanup (which itself takes b.mu) - holding the lock
+// across that call would self-deadlock this goroutine. Each branch below
+// therefore takes a short-lived RLock only to snapshot what it needs
+// (the target connection(s)), and forwards after releasing it.
+func (b *Broker) forwardMessage(from *Connection, msg Message) {
 	if from.isHost {
-		// Forward from host to client
-		if clientID, exists := b.hostClients[from.hostID]; exists {
-			if client, exists := b.clients[clientID]; exists {
-				b.sendMessage(client, msg)
+		// Forward from host to every attached client (controller, viewers,
+		// and recorders all receive host output read-only).
+		b.mu.RLock()
+		hostClients := b.hostClients[from.hostID]
+		clients := make([]*Connection, 0, len(hostClients))
+		for _, client := range hostClients {
+			clients = append(clients, client)
+		}
+		b.mu.RUnlock()
+
+		for _, client := range clients {
+			b.sendMessage(client, msg)
+		}
+		if len(clients) > 0 {
+			metrics.MessagesForwardedTotal.WithLabelValues(metrics.DirectionHostToClient).Add(float64(len(clients)))
+			metrics.BytesForwardedTotal.WithLabelValues(metrics.DirectionHostToClient).Add(float64(len(msg.Data) * len(clients)))
+		}
+		if b.recordingEnabled(from.hostID) {
+			if err := b.recorder.Write(from.sessionID, msg.Data); err != nil {
+				b.logger.WithError(err).WithField("hostID", from.hostID).Error("Failed to write session recording")
 			}
 		}
+		// In cluster mode, also publish to the session's "down" subject so
+		// any client attached through another node receives this output.
+		if b.cluster != nil {
+			b.publishToCluster(backplane.SessionSubject(from.sessionID, backplane.DirectionDown), from.hostID, msg, metrics.DirectionHostToClient)
+		}
 	} else {
-		// Forward from client to host
-		if host, exists := b.hosts[from.hostID]; exists {
+		// Only the controller's input is forwarded to the host; viewers
+		// and recorders are read-only and have their frames dropped.
+		if from.role != auth.RoleController {
+			return
+		}
+
+		b.mu.RLock()
+		host, exists := b.hosts[from.hostID]
+		b.mu.RUnlock()
+
+		if exists {
 			b.sendMessage(host, msg)
+			metrics.MessagesForwardedTotal.WithLabelValues(metrics.DirectionClientToHost).Inc()
+			metrics.BytesForwardedTotal.WithLabelValues(metrics.DirectionClientToHost).Add(float64(len(msg.Data)))
+			return
+		}
+		if from.remoteSessionID != "" {
+			b.publishToCluster(backplane.SessionSubject(from.remoteSessionID, backplane.DirectionUp), from.hostID, msg, metrics.DirectionClientToHost)
 		}
 	}
 }
 
+// publishToCluster encodes msg and publishes it to subject, logging (but
+// not propagating) any failure - a dropped cluster-forwarded frame is not
+// fatal to the connection that produced it.
+func (b *Broker) publishToCluster(subject, hostID string, msg Message, direction string) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		b.logger.WithError(err).WithField("hostID", hostID).Error("Failed to encode frame for cluster forwarding")
+		return
+	}
+	if err := b.cluster.Backplane.Publish(subject, data); err != nil {
+		b.logger.WithError(err).WithField("hostID", hostID).Error("Failed to publish frame to cluster")
+		return
+	}
+	metrics.MessagesForwardedTotal.WithLabelValues(direction).Inc()
+	metrics.BytesForwardedTotal.WithLabelValues(direction).Add(float64(len(msg.Data)))
+}
+
+// sendMessage enqueues msg for conn's writer goroutine. If the send buffer
+// is full, it applies backpressure: it blocks up to limits.SendDeadline
+// rather than dropping the frame immediately, and only tears down the
+// connection if the deadline passes with the buffer still full.
+//
+// Every attempt to send holds conn.sendMu and checks conn.closed first,
+// since conn.send is closed (by cleanup) under the same lock - that's what
+// keeps this from ever sending on an already-closed channel, which would
+// panic.
 func (b *Broker) sendMessage(conn *Connection, msg Message) {
+	conn.sendMu.Lock()
+	if conn.closed {
+		conn.sendMu.Unlock()
+		return
+	}
 	select {
 	case conn.send <- msg:
+		conn.sendMu.Unlock()
+		return
 	default:
+	}
+	conn.sendMu.Unlock()
+
+	metrics.SendNearOverflowTotal.Inc()
+
+	conn.sendMu.Lock()
+	if conn.closed {
+		conn.sendMu.Unlock()
+		return
+	}
+	select {
+	case conn.send <- msg:
+		conn.sendMu.Unlock()
+	case <-time.After(b.limits.SendDeadline):
+		conn.sendMu.Unlock()
+		b.logger.WithField("remoteAddr", conn.remoteAddr).Warn("Send buffer full past backpressure deadline, disconnecting")
 		b.cleanup(conn)
 	}
 }
 
+// certMatchesHostID reports whether hostID matches the peer certificate's
+// CommonName or any of its DNS SANs, so a host can only register under the
+// identity its certificate was issued for.
+func certMatchesHostID(cert *x509.Certificate, hostID string) bool {
+	if cert.Subject.CommonName == hostID {
+		return true
+	}
+	for _, san := range cert.DNSNames {
+		if san == hostID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectConnection closes conn with CloseAuthFailed and the given reason,
+// used instead of a bare conn.Close() whenever auth validation fails so the
+// peer can distinguish "rejected" from a network error. reason is also used
+// as the rtx_relay_connect_failures_total label and audit event field, so
+// keep it a short snake_case token.
+func (b *Broker) rejectConnection(conn *Connection, hostID, jti, reason string) {
+	metrics.ConnectFailuresTotal.WithLabelValues(reason).Inc()
+	b.auditEvent("auth_failure", conn, logrus.Fields{
+		"host_id": hostID,
+		"jti":     jti,
+		"reason":  reason,
+	})
+
+	closeMsg := websocket.FormatCloseMessage(CloseAuthFailed, reason)
+	conn.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	conn.conn.Close()
+}
+
+// closeSend closes conn.send, synchronized with sendMessage via conn.sendMu
+// so a send already in flight on it can never race this close. Safe to
+// call more than once; only the first call closes the channel.
+func (conn *Connection) closeSend() {
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+	if conn.closed {
+		return
+	}
+	conn.closed = true
+	close(conn.send)
+}
+
 func (b *Broker) cleanup(conn *Connection) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
 
+	var withdrawHostID string
 	if conn.isHost && conn.hostID != "" {
 		delete(b.hosts, conn.hostID)
-		if clientID, exists := b.hostClients[conn.hostID]; exists {
-			if client, exists := b.clients[clientID]; exists {
-				client.conn.Close()
-				delete(b.clients, clientID)
+		for clientID, client := range b.hostClients[conn.hostID] {
+			client.conn.Close()
+			delete(b.clients, clientID)
+		}
+		delete(b.hostClients, conn.hostID)
+		metrics.Hosts.Set(float64(len(b.hosts)))
+		metrics.Clients.Set(float64(len(b.clients)))
+		metrics.SessionDuration.Observe(time.Since(conn.connectedAt).Seconds())
+		if b.recordingEnabled(conn.hostID) {
+			if err := b.recorder.Close(conn.sessionID); err != nil {
+				b.logger.WithError(err).WithField("hostID", conn.hostID).Error("Failed to close session recording")
 			}
-			delete(b.hostClients, conn.hostID)
 		}
 		b.logger.WithField("hostID", conn.hostID).Info("Host disconnected")
+		b.auditEvent("disconnect", conn, logrus.Fields{"host_id": conn.hostID})
+		withdrawHostID = conn.hostID
 	} else if !conn.isHost && conn.clientID != "" {
 		delete(b.clients, conn.clientID)
 		if conn.hostID != "" {
-			delete(b.hostClients, conn.hostID)
+			delete(b.hostClients[conn.hostID], conn.clientID)
 		}
+		metrics.Clients.Set(float64(len(b.clients)))
 		b.logger.WithField("clientID", conn.clientID).Info("Client disconnected")
+		b.auditEvent("disconnect", conn, logrus.Fields{"host_id": conn.hostID, "client_id": conn.clientID})
 	}
 
-	close(conn.send)
+	conn.closeSend()
+	b.mu.Unlock()
+
+	// The backplane calls below are network I/O, so they run after
+	// releasing b.mu rather than under it.
+	if conn.stopHeartbeat != nil {
+		close(conn.stopHeartbeat)
+	}
+	if conn.clusterUnsub != nil {
+		if err := conn.clusterUnsub(); err != nil {
+			b.logger.WithError(err).Error("Failed to unsubscribe cluster session subject")
+		}
+	}
+	if withdrawHostID != "" && b.cluster != nil {
+		if err := b.cluster.Backplane.Withdraw(withdrawHostID); err != nil {
+			b.logger.WithError(err).WithField("hostID", withdrawHostID).Error("Failed to withdraw cluster presence")
+		}
+	}
+}
+
+// Drain closes every host and client connection on this node, so a node
+// shutting down ends sessions cleanly instead of leaving stale presence
+// records for other nodes to route to it. Cluster mode does not migrate a
+// live session to another node; clients reconnect and re-resolve presence
+// once their host re-registers elsewhere.
+func (b *Broker) Drain() {
+	b.mu.RLock()
+	conns := make([]*Connection, 0, len(b.hosts)+len(b.clients))
+	for _, host := range b.hosts {
+		conns = append(conns, host)
+	}
+	for _, client := range b.clients {
+		conns = append(conns, client)
+	}
+	b.mu.RUnlock()
+
+	for _, conn := range conns {
+		conn.conn.Close()
+	}
 }
 
 func (b *Broker) GetStats() map[string]interface{} {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	pairs := 0
+	for _, clients := range b.hostClients {
+		if len(clients) > 0 {
+			pairs++
+		}
+	}
+
 	return map[string]interface{}{
 		"hosts":   len(b.hosts),
 		"clients": len(b.clients),
-		"pairs":   len(b.hostClients),
+		"pairs":   pairs,
 	}
 }
\ No newline at end of file
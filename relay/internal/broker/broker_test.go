@@ -0,0 +1,96 @@
+package broker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rtx/relay/internal/auth"
+	"github.com/rtx/relay/internal/registry"
+)
+
+func newTestBroker(t *testing.T, hostID, deviceKey string) (*Broker, *auth.JWTManager) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	jwtManager := auth.NewJWTManager("test-secret")
+	devices := registry.NewStaticRegistry(map[string]string{hostID: deviceKey})
+	return NewBroker(logger, logger, jwtManager, devices, nil, nil, Limits{}, nil), jwtManager
+}
+
+func dialWS(t *testing.T, wsURL string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	return conn
+}
+
+// TestControllerTakeoverConcurrentDoesNotPanic reproduces the race where a
+// controller takeover force-closes the displaced connection while a
+// takeover notice is still in flight to it: the displaced connection's own
+// read loop errors out of the closed socket and calls cleanup concurrently
+// with this goroutine's sendMessage, racing the close of conn.send against
+// the send itself. Run with -race.
+func TestControllerTakeoverConcurrentDoesNotPanic(t *testing.T) {
+	const hostID = "host-1"
+	const deviceKey = "device-1"
+
+	b, jwtManager := newTestBroker(t, hostID, deviceKey)
+	server := httptest.NewServer(http.HandlerFunc(b.HandleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	hostToken, err := jwtManager.GenerateConnectToken(hostID, deviceKey)
+	if err != nil {
+		t.Fatalf("GenerateConnectToken: %v", err)
+	}
+	hostConn := dialWS(t, wsURL)
+	defer hostConn.Close()
+	if err := hostConn.WriteJSON(Message{Type: MsgHostRegister, HostID: hostID, Token: hostToken}); err != nil {
+		t.Fatalf("host register: %v", err)
+	}
+	go drain(hostConn)
+
+	controllerToken, err := jwtManager.GenerateClientToken(hostID, deviceKey, auth.RoleController)
+	if err != nil {
+		t.Fatalf("GenerateClientToken: %v", err)
+	}
+
+	const attempts = 200
+	var wg sync.WaitGroup
+	wg.Add(attempts)
+	for i := 0; i < attempts; i++ {
+		go func() {
+			defer wg.Done()
+			conn := dialWS(t, wsURL)
+			defer conn.Close()
+			if err := conn.WriteJSON(Message{Type: MsgClientConnect, HostID: hostID, Token: controllerToken}); err != nil {
+				return
+			}
+			drain(conn)
+		}()
+	}
+	wg.Wait()
+}
+
+// drain reads (and discards) messages off conn until it errors or closes,
+// standing in for a real client's read loop so the server-side connection
+// behaves as it would in production (and its writer goroutine doesn't
+// block forever on a full send buffer).
+func drain(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package broker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/rtx/relay/internal/auth"
+)
+
+func TestHandleClientConnectRejectsUnauthorizedDeviceKey(t *testing.T) {
+	const hostID = "host-1"
+
+	b, jwtManager := newTestBroker(t, hostID, "the-real-device-key")
+	server := httptest.NewServer(http.HandlerFunc(b.HandleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	hostToken, err := jwtManager.GenerateConnectToken(hostID, "the-real-device-key")
+	if err != nil {
+		t.Fatalf("GenerateConnectToken: %v", err)
+	}
+	hostConn := dialWS(t, wsURL)
+	defer hostConn.Close()
+	if err := hostConn.WriteJSON(Message{Type: MsgHostRegister, HostID: hostID, Token: hostToken}); err != nil {
+		t.Fatalf("host register: %v", err)
+	}
+	go drain(hostConn)
+
+	// A client token bound to a device key the registry doesn't recognize
+	// for this host (e.g. one issued before the key was rotated/revoked)
+	// must be rejected even though the JWT signature and hostID claim are
+	// otherwise valid.
+	staleToken, err := jwtManager.GenerateClientToken(hostID, "a-revoked-device-key", auth.RoleViewer)
+	if err != nil {
+		t.Fatalf("GenerateClientToken: %v", err)
+	}
+	clientConn := dialWS(t, wsURL)
+	defer clientConn.Close()
+	if err := clientConn.WriteJSON(Message{Type: MsgClientConnect, HostID: hostID, Token: staleToken}); err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+
+	clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = clientConn.ReadMessage()
+	if err == nil {
+		t.Fatal("expected the connection to be closed as unauthorized, got a message instead")
+	}
+	closeErr, ok := err.(*websocket.CloseError)
+	if !ok {
+		t.Fatalf("expected a websocket close error, got %T: %v", err, err)
+	}
+	if closeErr.Code != CloseAuthFailed {
+		t.Errorf("close code = %d, want %d (CloseAuthFailed)", closeErr.Code, CloseAuthFailed)
+	}
+}
@@ -0,0 +1,140 @@
+package broker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// BinarySubprotocol is the WebSocket subprotocol a host or client negotiates
+// at upgrade time to switch the connection from JSON text frames to the
+// compact binary framing below. Connections that don't request it (or
+// request none) keep using WriteJSON/ReadJSON.
+const BinarySubprotocol = "rtx.binary.v1"
+
+var subprotocols = []string{BinarySubprotocol}
+
+var binaryMsgTypes = map[MessageType]byte{
+	MsgHostRegister:       1,
+	MsgClientConnect:      2,
+	MsgHostRegistered:     3,
+	MsgClientReady:        4,
+	MsgForward:            5,
+	MsgPing:               6,
+	MsgPong:               7,
+	MsgControllerTakeover: 8,
+}
+
+var binaryMsgTypeNames = func() map[byte]MessageType {
+	names := make(map[byte]MessageType, len(binaryMsgTypes))
+	for name, b := range binaryMsgTypes {
+		names[b] = name
+	}
+	return names
+}()
+
+// encodeBinary packs a Message into a compact, length-prefixed binary
+// frame: 1-byte type tag, then hostID/token/data each as a uint16-or-uint32
+// length prefix followed by the raw bytes, then an 8-byte Unix nano
+// timestamp. This is used instead of JSON when BinarySubprotocol is
+// negotiated, to avoid the overhead of per-field text encoding for high
+// frequency terminal data.
+func encodeBinary(msg Message) ([]byte, error) {
+	tag, ok := binaryMsgTypes[msg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type for binary framing: %s", msg.Type)
+	}
+
+	hostID := []byte(msg.HostID)
+	token := []byte(msg.Token)
+	if len(hostID) > 0xFFFF || len(token) > 0xFFFF {
+		return nil, fmt.Errorf("hostID/token too long for binary framing")
+	}
+
+	buf := make([]byte, 0, 1+2+len(hostID)+2+len(token)+4+len(msg.Data)+8)
+	buf = append(buf, tag)
+	buf = appendUint16Field(buf, hostID)
+	buf = appendUint16Field(buf, token)
+	buf = appendUint32Field(buf, msg.Data)
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(msg.Timestamp.UnixNano()))
+	buf = append(buf, ts[:]...)
+
+	return buf, nil
+}
+
+// decodeBinary is the inverse of encodeBinary.
+func decodeBinary(data []byte) (Message, error) {
+	if len(data) < 1 {
+		return Message{}, fmt.Errorf("binary frame too short")
+	}
+	msgType, ok := binaryMsgTypeNames[data[0]]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown binary frame type tag: %d", data[0])
+	}
+	rest := data[1:]
+
+	hostID, rest, err := readUint16Field(rest)
+	if err != nil {
+		return Message{}, err
+	}
+	token, rest, err := readUint16Field(rest)
+	if err != nil {
+		return Message{}, err
+	}
+	payload, rest, err := readUint32Field(rest)
+	if err != nil {
+		return Message{}, err
+	}
+	if len(rest) < 8 {
+		return Message{}, fmt.Errorf("binary frame missing timestamp")
+	}
+	ts := time.Unix(0, int64(binary.BigEndian.Uint64(rest[:8])))
+
+	return Message{
+		Type:      msgType,
+		HostID:    string(hostID),
+		Token:     string(token),
+		Data:      payload,
+		Timestamp: ts,
+	}, nil
+}
+
+func appendUint16Field(buf, field []byte) []byte {
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(field)))
+	buf = append(buf, l[:]...)
+	return append(buf, field...)
+}
+
+func appendUint32Field(buf, field []byte) []byte {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(field)))
+	buf = append(buf, l[:]...)
+	return append(buf, field...)
+}
+
+func readUint16Field(data []byte) (field, rest []byte, err error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("binary frame truncated reading field length")
+	}
+	n := binary.BigEndian.Uint16(data[:2])
+	data = data[2:]
+	if len(data) < int(n) {
+		return nil, nil, fmt.Errorf("binary frame truncated reading field body")
+	}
+	return data[:n], data[n:], nil
+}
+
+func readUint32Field(data []byte) (field, rest []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("binary frame truncated reading field length")
+	}
+	n := binary.BigEndian.Uint32(data[:4])
+	data = data[4:]
+	if uint32(len(data)) < n {
+		return nil, nil, fmt.Errorf("binary frame truncated reading field body")
+	}
+	return data[:n], data[n:], nil
+}
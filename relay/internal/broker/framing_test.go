@@ -0,0 +1,73 @@
+package broker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeBinaryRoundTrip(t *testing.T) {
+	msg := Message{
+		Type:      MsgForward,
+		HostID:    "host-123",
+		Token:     "tok-abc",
+		Data:      []byte("\x1b[31mhello\x00world\x1b[0m"),
+		Timestamp: time.Unix(0, 1700000000123456789),
+	}
+
+	encoded, err := encodeBinary(msg)
+	if err != nil {
+		t.Fatalf("encodeBinary: %v", err)
+	}
+
+	decoded, err := decodeBinary(encoded)
+	if err != nil {
+		t.Fatalf("decodeBinary: %v", err)
+	}
+
+	if decoded.Type != msg.Type {
+		t.Errorf("Type = %q, want %q", decoded.Type, msg.Type)
+	}
+	if decoded.HostID != msg.HostID {
+		t.Errorf("HostID = %q, want %q", decoded.HostID, msg.HostID)
+	}
+	if decoded.Token != msg.Token {
+		t.Errorf("Token = %q, want %q", decoded.Token, msg.Token)
+	}
+	if string(decoded.Data) != string(msg.Data) {
+		t.Errorf("Data = %q, want %q", decoded.Data, msg.Data)
+	}
+	if !decoded.Timestamp.Equal(msg.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", decoded.Timestamp, msg.Timestamp)
+	}
+}
+
+func TestEncodeBinaryUnknownType(t *testing.T) {
+	if _, err := encodeBinary(Message{Type: MessageType("bogus")}); err == nil {
+		t.Fatal("expected error for unknown message type, got nil")
+	}
+}
+
+func TestDecodeBinaryTruncatedFrame(t *testing.T) {
+	full, err := encodeBinary(Message{
+		Type:      MsgPing,
+		HostID:    "host-1",
+		Token:     "tok-1",
+		Data:      []byte("payload"),
+		Timestamp: time.Unix(0, 42),
+	})
+	if err != nil {
+		t.Fatalf("encodeBinary: %v", err)
+	}
+
+	for n := 0; n < len(full); n++ {
+		if _, err := decodeBinary(full[:n]); err == nil {
+			t.Errorf("decodeBinary(%d of %d bytes) = nil error, want truncation error", n, len(full))
+		}
+	}
+}
+
+func TestDecodeBinaryUnknownTypeTag(t *testing.T) {
+	if _, err := decodeBinary([]byte{0xFF}); err == nil {
+		t.Fatal("expected error for unknown type tag, got nil")
+	}
+}
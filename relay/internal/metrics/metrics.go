@@ -0,0 +1,62 @@
+// Package metrics defines the relay's Prometheus instrumentation. Broker
+// updates these on every connect, forward, and disconnect; cmd/relay-server
+// exposes them at /metrics via promhttp.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Direction label values for MessagesForwardedTotal and BytesForwardedTotal.
+const (
+	DirectionHostToClient = "host_to_client"
+	DirectionClientToHost = "client_to_host"
+)
+
+var (
+	Hosts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rtx_relay_hosts",
+		Help: "Number of hosts currently registered with this relay.",
+	})
+
+	Clients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rtx_relay_clients",
+		Help: "Number of clients currently connected to this relay.",
+	})
+
+	MessagesForwardedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rtx_relay_messages_forwarded_total",
+		Help: "Total messages forwarded between hosts and clients.",
+	}, []string{"direction"})
+
+	BytesForwardedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rtx_relay_bytes_forwarded_total",
+		Help: "Total payload bytes forwarded between hosts and clients.",
+	}, []string{"direction"})
+
+	ConnectFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rtx_relay_connect_failures_total",
+		Help: "Total host/client connections rejected, by reason.",
+	}, []string{"reason"})
+
+	SessionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "rtx_relay_session_duration_seconds",
+		Help:    "Duration of host sessions from registration to disconnect.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 15), // 1s .. ~4.5h
+	})
+
+	SendNearOverflowTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rtx_relay_send_near_overflow_total",
+		Help: "Total times a connection's send buffer was full and backpressure was applied.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		Hosts,
+		Clients,
+		MessagesForwardedTotal,
+		BytesForwardedTotal,
+		ConnectFailuresTotal,
+		SessionDuration,
+		SendNearOverflowTotal,
+	)
+}
@@ -0,0 +1,75 @@
+// Package ratelimit implements a simple token-bucket limiter used to bound
+// how much data and how many messages a single connection may push through
+// the broker per second.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a pair of token buckets, one for bytes and one for messages,
+// refilled continuously at bytesPerSec/msgsPerSec. A zero-value rate
+// disables that bucket (Allow always succeeds for it).
+type Limiter struct {
+	mu sync.Mutex
+
+	bytesPerSec float64
+	msgsPerSec  float64
+
+	byteTokens float64
+	msgTokens  float64
+	last       time.Time
+}
+
+// NewLimiter returns a Limiter allowing up to bytesPerSec bytes and
+// msgsPerSec messages per second, each bucket initially full.
+func NewLimiter(bytesPerSec, msgsPerSec float64) *Limiter {
+	return &Limiter{
+		bytesPerSec: bytesPerSec,
+		msgsPerSec:  msgsPerSec,
+		byteTokens:  bytesPerSec,
+		msgTokens:   msgsPerSec,
+		last:        time.Now(),
+	}
+}
+
+// Allow reports whether a single message of n bytes may be admitted right
+// now, consuming one message token and n byte tokens if so.
+func (l *Limiter) Allow(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+
+	if l.bytesPerSec > 0 {
+		l.byteTokens = minFloat(l.byteTokens+elapsed*l.bytesPerSec, l.bytesPerSec)
+	}
+	if l.msgsPerSec > 0 {
+		l.msgTokens = minFloat(l.msgTokens+elapsed*l.msgsPerSec, l.msgsPerSec)
+	}
+
+	if l.bytesPerSec > 0 && l.byteTokens < float64(n) {
+		return false
+	}
+	if l.msgsPerSec > 0 && l.msgTokens < 1 {
+		return false
+	}
+
+	if l.bytesPerSec > 0 {
+		l.byteTokens -= float64(n)
+	}
+	if l.msgsPerSec > 0 {
+		l.msgTokens--
+	}
+	return true
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+// Package recorder tees host output into asciicast v2 / ttyrec-compatible
+// recordings so a session can be replayed later. Recording is pluggable:
+// Recorder only describes the write path, so a local-disk implementation
+// (FileRecorder) and future backends (e.g. S3) can share the same call
+// sites in broker.Broker.
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder tees host output frames for a session to storage. Write may be
+// called many times per session; Close finalizes the recording once the
+// session ends.
+type Recorder interface {
+	Write(sessionID string, data []byte) error
+	Close(sessionID string) error
+}
+
+// Store opens a previously recorded session for replay.
+type Store interface {
+	Open(sessionID string) (io.ReadCloser, error)
+}
+
+// header is the asciicast v2 header line written once per recording.
+type header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// FileRecorder records sessions as asciicast v2 files under baseDir, named
+// "<sessionID>.cast", and serves them back as a Store.
+type FileRecorder struct {
+	baseDir string
+
+	mu       sync.Mutex
+	sessions map[string]*recording
+}
+
+type recording struct {
+	mu    sync.Mutex
+	file  *os.File
+	start time.Time
+}
+
+// NewFileRecorder returns a FileRecorder that writes recordings under
+// baseDir, creating it if necessary.
+func NewFileRecorder(baseDir string) (*FileRecorder, error) {
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	return &FileRecorder{
+		baseDir:  baseDir,
+		sessions: make(map[string]*recording),
+	}, nil
+}
+
+func (f *FileRecorder) path(sessionID string) string {
+	return filepath.Join(f.baseDir, sessionID+".cast")
+}
+
+// Write appends data as an "o" (output) frame, opening and writing the
+// asciicast header on the session's first call.
+func (f *FileRecorder) Write(sessionID string, data []byte) error {
+	rec, err := f.recordingFor(sessionID)
+	if err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	frame := []interface{}{time.Since(rec.start).Seconds(), "o", string(data)}
+	line, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	_, err = rec.file.Write(append(line, '\n'))
+	return err
+}
+
+func (f *FileRecorder) recordingFor(sessionID string) (*recording, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if rec, ok := f.sessions[sessionID]; ok {
+		return rec, nil
+	}
+
+	file, err := os.Create(f.path(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	rec := &recording{file: file, start: time.Now()}
+	hdr := header{
+		Version:   2,
+		Width:     defaultWidth,
+		Height:    defaultHeight,
+		Timestamp: rec.start.Unix(),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	}
+	line, err := json.Marshal(hdr)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	f.sessions[sessionID] = rec
+	return rec, nil
+}
+
+// Close finalizes the recording file for sessionID, if one is open.
+func (f *FileRecorder) Close(sessionID string) error {
+	f.mu.Lock()
+	rec, ok := f.sessions[sessionID]
+	delete(f.sessions, sessionID)
+	f.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return rec.file.Close()
+}
+
+// Open implements Store by returning the recording file for replay.
+func (f *FileRecorder) Open(sessionID string) (io.ReadCloser, error) {
+	return os.Open(f.path(sessionID))
+}
@@ -0,0 +1,71 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Replay reads an asciicast v2 recording from src and writes its output
+// frames to w, pacing each frame according to its recorded timestamp
+// divided by speed (speed <= 0 is treated as 1, i.e. real-time). If flush
+// is non-nil it is called after every frame, so HTTP handlers can stream
+// the response as it plays out.
+func Replay(w io.Writer, src io.Reader, speed float64, flush func()) error {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	var hdr header
+	if err := json.Unmarshal(scanner.Bytes(), &hdr); err != nil {
+		return fmt.Errorf("failed to parse asciicast header: %w", err)
+	}
+
+	start := time.Now()
+	for scanner.Scan() {
+		var frame []json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return fmt.Errorf("failed to parse asciicast frame: %w", err)
+		}
+		if len(frame) != 3 {
+			continue
+		}
+
+		var offset float64
+		var kind, data string
+		if err := json.Unmarshal(frame[0], &offset); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(frame[1], &kind); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(frame[2], &data); err != nil {
+			return err
+		}
+		if kind != "o" {
+			continue
+		}
+
+		target := start.Add(time.Duration(offset / speed * float64(time.Second)))
+		if d := time.Until(target); d > 0 {
+			time.Sleep(d)
+		}
+
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,49 @@
+// Package registry provides server-side lookup of which device key is
+// authorized to register a given host ID, independent of the JWT that
+// merely proves possession of a signed connect token.
+package registry
+
+import "sync"
+
+// DeviceRegistry answers whether deviceKey is the one on file for hostID.
+// Implementations may be backed by config, a database, or any other store.
+type DeviceRegistry interface {
+	Authorized(hostID, deviceKey string) bool
+}
+
+// StaticRegistry is a DeviceRegistry backed by an in-memory hostID -> deviceKey
+// map, typically loaded once from config at startup.
+type StaticRegistry struct {
+	mu      sync.RWMutex
+	devices map[string]string
+}
+
+// NewStaticRegistry builds a StaticRegistry from a hostID -> deviceKey map.
+// A nil map produces an empty registry that authorizes nothing.
+func NewStaticRegistry(devices map[string]string) *StaticRegistry {
+	copied := make(map[string]string, len(devices))
+	for k, v := range devices {
+		copied[k] = v
+	}
+	return &StaticRegistry{devices: copied}
+}
+
+// Authorized reports whether deviceKey matches the key on file for hostID.
+// An empty deviceKey is never authorized, even if the key on file for hostID
+// is also empty.
+func (r *StaticRegistry) Authorized(hostID, deviceKey string) bool {
+	if deviceKey == "" {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.devices[hostID]
+	return ok && key == deviceKey
+}
+
+// Set records or updates the device key authorized for hostID.
+func (r *StaticRegistry) Set(hostID, deviceKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.devices[hostID] = deviceKey
+}
@@ -0,0 +1,56 @@
+package registry
+
+import "testing"
+
+func TestStaticRegistryAuthorized(t *testing.T) {
+	r := NewStaticRegistry(map[string]string{"host-1": "key-1"})
+
+	if !r.Authorized("host-1", "key-1") {
+		t.Error("Authorized(host-1, key-1) = false, want true")
+	}
+	if r.Authorized("host-1", "wrong-key") {
+		t.Error("Authorized(host-1, wrong-key) = true, want false")
+	}
+	if r.Authorized("host-2", "key-1") {
+		t.Error("Authorized(host-2, key-1) = true, want false for unregistered host")
+	}
+	if r.Authorized("host-1", "") {
+		t.Error("Authorized(host-1, \"\") = true, want false for empty device key")
+	}
+}
+
+func TestStaticRegistryEmptyDeviceKeyNeverAuthorized(t *testing.T) {
+	// A host registered with an empty device key (e.g. a zero-value config
+	// entry) must still never authorize an empty-key request - that would
+	// reopen the auth bypass this registry exists to close.
+	r := NewStaticRegistry(map[string]string{"host-1": ""})
+
+	if r.Authorized("host-1", "") {
+		t.Error("Authorized(host-1, \"\") = true, want false even when the stored key is also empty")
+	}
+}
+
+func TestStaticRegistrySet(t *testing.T) {
+	r := NewStaticRegistry(nil)
+
+	if r.Authorized("host-1", "key-1") {
+		t.Fatal("Authorized on empty registry = true, want false")
+	}
+
+	r.Set("host-1", "key-1")
+
+	if !r.Authorized("host-1", "key-1") {
+		t.Error("Authorized(host-1, key-1) after Set = false, want true")
+	}
+}
+
+func TestNewStaticRegistryCopiesInput(t *testing.T) {
+	devices := map[string]string{"host-1": "key-1"}
+	r := NewStaticRegistry(devices)
+
+	devices["host-1"] = "mutated"
+
+	if !r.Authorized("host-1", "key-1") {
+		t.Error("registry was affected by mutating the map passed to NewStaticRegistry, want it isolated")
+	}
+}
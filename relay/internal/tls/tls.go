@@ -2,29 +2,107 @@ package tls
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"os"
+	"sync/atomic"
 )
 
-// LoadTLSConfig loads TLS configuration from certificate and key files
-func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
-	// Check if files exist
-	if _, err := os.Stat(certFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("certificate file not found: %s", certFile)
+// Manager owns the relay's server certificate and, when mutual TLS is
+// enabled, the client CA pool used to verify host/client certificates. Both
+// can be swapped at runtime via Reload so operators can rotate certs
+// without restarting the process.
+type Manager struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	cert   atomic.Value // tls.Certificate
+	caPool atomic.Value // *x509.CertPool, nil unless clientCAFile is set
+}
+
+// NewManager loads certFile/keyFile and, if clientCAFile is non-empty, the
+// client CA bundle, then returns a Manager ready to serve TLSConfig.
+func NewManager(certFile, keyFile, clientCAFile string) (*Manager, error) {
+	m := &Manager{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
 	}
-	if _, err := os.Stat(keyFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("key file not found: %s", keyFile)
+	if err := m.Reload(); err != nil {
+		return nil, err
 	}
+	return m, nil
+}
 
-	// Load certificate
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// Reload re-reads the server certificate and client CA bundle from disk and
+// atomically swaps them in. Existing connections are unaffected; new
+// handshakes pick up the reloaded material.
+func (m *Manager) Reload() error {
+	if _, err := os.Stat(m.certFile); os.IsNotExist(err) {
+		return fmt.Errorf("certificate file not found: %s", m.certFile)
+	}
+	if _, err := os.Stat(m.keyFile); os.IsNotExist(err) {
+		return fmt.Errorf("key file not found: %s", m.keyFile)
+	}
+
+	cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load X509 key pair: %w", err)
+		return fmt.Errorf("failed to load X509 key pair: %w", err)
 	}
+	m.cert.Store(cert)
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS12,
+	if m.clientCAFile != "" {
+		pool, err := loadCAPool(m.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client CA pool: %w", err)
+		}
+		m.caPool.Store(pool)
+	}
+
+	return nil
+}
+
+// loadCAPool parses every CERTIFICATE block in a (possibly multi-cert) PEM
+// file into an x509.CertPool.
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		pool.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// TLSConfig returns a *tls.Config whose certificate and (if mutual TLS is
+// enabled) client CA pool are resolved lazily from the Manager's current
+// state, so a Reload takes effect on the next handshake.
+func (m *Manager) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
 		CipherSuites: []uint16{
 			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
@@ -32,5 +110,32 @@ func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
 			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
 		},
 		PreferServerCipherSuites: true,
-	}, nil
-}
\ No newline at end of file
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert := m.cert.Load().(tls.Certificate)
+			return &cert, nil
+		},
+	}
+
+	if m.clientCAFile != "" {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clone := cfg.Clone()
+			clone.GetConfigForClient = nil
+			clone.ClientCAs, _ = m.caPool.Load().(*x509.CertPool)
+			return clone, nil
+		}
+	}
+
+	return cfg
+}
+
+// LoadTLSConfig loads a one-shot, non-reloadable TLS configuration from
+// certificate and key files. Kept for callers that don't need mutual TLS or
+// runtime reload; prefer NewManager for the relay server itself.
+func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	m, err := NewManager(certFile, keyFile, "")
+	if err != nil {
+		return nil, err
+	}
+	return m.TLSConfig(), nil
+}